@@ -0,0 +1,24 @@
+package authority
+
+import (
+	acme "github.com/smallstep/certificates/acme"
+)
+
+// OCFACMEAuthority wraps an upstream ACME authority so that certificates
+// issued through ACME for an OCF provisioner (one with `ocf: true` set) go
+// through the same OCF identity/role profile as OCFSign, instead of
+// stepAuth's signing path directly.
+type OCFACMEAuthority struct {
+	*acme.Authority
+}
+
+// NewOCFACMEAuthority returns an ACME authority backed by a. Because the
+// ACME authority signs finalized orders through a.Sign, orders coming from
+// an OCF provisioner are transparently routed through OCFSign.
+func NewOCFACMEAuthority(a *Authority, opts ...acme.AuthorityOption) (*OCFACMEAuthority, error) {
+	acmeAuth, err := acme.NewAuthority(a.GetDatabase(), a, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &OCFACMEAuthority{Authority: acmeAuth}, nil
+}
@@ -0,0 +1,76 @@
+package authority
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// OCFPolicyHandler exposes OCFPolicyDB's read and create/update operations
+// over HTTP (GET and PUT), so a fleet operator can update a device's
+// allowed OCF roles/claims without restarting the CA. There is no delete.
+// Mount it under the admin API, keyed by device ID, e.g.:
+//
+//	mux.Handle("/admin/ocf/policy/", authority.NewOCFPolicyHandler(auth))
+type OCFPolicyHandler struct {
+	auth *Authority
+}
+
+// NewOCFPolicyHandler returns an http.Handler serving GET and PUT of the
+// OCF role policy for auth's OCFPolicyDB.
+func NewOCFPolicyHandler(auth *Authority) *OCFPolicyHandler {
+	return &OCFPolicyHandler{auth: auth}
+}
+
+// ServeHTTP handles GET and PUT requests for the device ID in the last
+// path segment of the request, e.g. GET/PUT /admin/ocf/policy/<deviceID>.
+func (h *OCFPolicyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	deviceID := path.Base(r.URL.Path)
+	// path.Base drops a trailing slash, so ".../policy/" would otherwise
+	// yield "policy" as the device ID.
+	if strings.HasSuffix(r.URL.Path, "/") || deviceID == "/" || deviceID == "." {
+		http.Error(w, "missing device id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getRolePolicy(w, deviceID)
+	case http.MethodPut:
+		h.putRolePolicy(w, r, deviceID)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *OCFPolicyHandler) getRolePolicy(w http.ResponseWriter, deviceID string) {
+	policy, err := h.auth.GetOCFRolePolicy(deviceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeOCFPolicyJSON(w, policy)
+}
+
+func (h *OCFPolicyHandler) putRolePolicy(w http.ResponseWriter, r *http.Request, deviceID string) {
+	var policy RolePolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, errors.Wrap(err, "error decoding role policy").Error(), http.StatusBadRequest)
+		return
+	}
+	policy.DeviceID = deviceID
+
+	if err := h.auth.PutOCFRolePolicy(&policy); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeOCFPolicyJSON(w, &policy)
+}
+
+func writeOCFPolicyJSON(w http.ResponseWriter, policy *RolePolicy) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
@@ -2,13 +2,16 @@ package authority
 
 import (
 	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
+	"sync"
 
 	"github.com/smallstep/certificates/authority"
 	stepAuthority "github.com/smallstep/certificates/authority"
 	stepProvisioner "github.com/smallstep/certificates/authority/provisioner"
 	"github.com/smallstep/certificates/db"
+	"github.com/smallstep/certificates/kms"
 	"github.com/smallstep/cli/crypto/pemutil"
 	"github.com/smallstep/cli/crypto/tlsutil"
 	"github.com/smallstep/cli/crypto/x509util"
@@ -19,11 +22,41 @@ const (
 	legacyAuthority = "step-certificate-authority"
 )
 
+// stepAuthRef wraps a stepAuthority.Authority generation with a WaitGroup
+// tracking requests currently in flight against it, so Reload can wait for
+// them to drain before shutting the old generation down.
+type stepAuthRef struct {
+	auth *stepAuthority.Authority
+	wg   sync.WaitGroup
+}
+
+// release marks one in-flight call against ref as done.
+func (ref *stepAuthRef) release() {
+	ref.wg.Done()
+}
+
 // Authority implements the Certificate Authority internal interface.
 type Authority struct {
+	// mu protects every field below from concurrent reads during a Reload.
+	mu                   sync.RWMutex
 	config               *Config
-	stepAuth             *stepAuthority.Authority
+	stepAuthRef          *stepAuthRef
 	intermediateIdentity *x509util.Identity
+
+	rootsBundle           string
+	federatedRootsBundle  string
+	bundledRoots          []*x509.Certificate
+	bundledFederatedRoots []*x509.Certificate
+
+	keyManager         kms.KeyManager
+	intermediateSigner crypto.Signer
+	intermediateCert   *x509.Certificate
+
+	ocfIdentityFunc OCFIdentityFunc
+	policyDB        OCFPolicyDB
+
+	// reloadMu serializes calls to Reload.
+	reloadMu sync.Mutex
 }
 
 type Option interface{}
@@ -37,6 +70,25 @@ func WithDatabase(db db.AuthDB) stepAuthority.Option {
 	return stepAuthority.WithDatabase(db)
 }
 
+// WithRootsBundle sets a PEM bundle of additional root certificates that
+// GetRoots() and GetRootCertificates() will trust alongside step-ca's own
+// roots, without rebuilding step-ca's internal trust store.
+func WithRootsBundle(path string) WrapperOption {
+	return func(a *Authority) {
+		a.rootsBundle = path
+	}
+}
+
+// WithFederatedRootsBundle sets a PEM bundle of additional federated roots
+// that GetFederation() will return alongside step-ca's own federated roots.
+// This lets multiple OCF CAs cross-federate without each one being
+// re-initialized with the other's roots.
+func WithFederatedRootsBundle(path string) WrapperOption {
+	return func(a *Authority) {
+		a.federatedRootsBundle = path
+	}
+}
+
 // New creates and initiates a new Authority type.
 func New(config *Config, opts ...Option) (*Authority, error) {
 	var stepOpts []stepAuthority.Option
@@ -55,11 +107,38 @@ func New(config *Config, opts ...Option) (*Authority, error) {
 		return nil, err
 	}
 
-	var intermediateIdentity *x509util.Identity
+	a := &Authority{
+		config:               config,
+		stepAuthRef:          &stepAuthRef{auth: stepAuth},
+		rootsBundle:          config.RootsBundle,
+		federatedRootsBundle: config.FederatedRootsBundle,
+	}
+
+	for _, wo := range wrapOpts {
+		wo(a)
+	}
 
-	// Decrypt and load intermediate public / private key pair.
-	if len(config.Password) > 0 {
-		intermediateIdentity, err = x509util.LoadIdentityFromDisk(
+	if a.rootsBundle != "" {
+		if a.bundledRoots, err = loadCertBundle(a.rootsBundle); err != nil {
+			return nil, err
+		}
+	}
+	if a.federatedRootsBundle != "" {
+		if a.bundledFederatedRoots, err = loadCertBundle(a.federatedRootsBundle); err != nil {
+			return nil, err
+		}
+	}
+
+	// Set up the intermediate signer: a KMS-backed signer if one was
+	// configured, via WithKeyManager or config.KMS, or else the on-disk
+	// intermediate public / private key pair, decrypted if config.Password
+	// is set.
+	if a.keyManager != nil || config.KMS != nil {
+		if err := a.initKeyManager(config); err != nil {
+			return nil, err
+		}
+	} else if len(config.Password) > 0 {
+		a.intermediateIdentity, err = x509util.LoadIdentityFromDisk(
 			config.IntermediateCert,
 			config.IntermediateKey,
 			pemutil.WithPassword([]byte(config.Password)),
@@ -68,99 +147,165 @@ func New(config *Config, opts ...Option) (*Authority, error) {
 			return nil, err
 		}
 	} else {
-		intermediateIdentity, err = x509util.LoadIdentityFromDisk(config.IntermediateCert, config.IntermediateKey)
+		a.intermediateIdentity, err = x509util.LoadIdentityFromDisk(config.IntermediateCert, config.IntermediateKey)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return &Authority{
-		config:               config,
-		stepAuth:             stepAuth,
-		intermediateIdentity: intermediateIdentity,
-	}, nil
+	return a, nil
+}
+
+// acquireStepAuth returns the current stepAuth generation with an in-flight
+// call registered against it, guarding against a concurrent Reload swapping
+// it out from under the caller. The caller must defer ref.release() once it
+// is done with ref.auth, so Reload can wait for in-flight calls like this
+// one to drain before shutting the old generation down.
+func (a *Authority) acquireStepAuth() *stepAuthRef {
+	a.mu.RLock()
+	ref := a.stepAuthRef
+	ref.wg.Add(1)
+	a.mu.RUnlock()
+	return ref
 }
 
 // GetDatabase returns the authority database. If the configuration does not
 // define a database, GetDatabase will return a db.SimpleDB instance.
 func (a *Authority) GetDatabase() db.AuthDB {
-	return a.stepAuth.GetDatabase()
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.GetDatabase()
 }
 
 // Shutdown safely shuts down any clients, databases, etc. held by the Authority.
 func (a *Authority) Shutdown() error {
-	return a.stepAuth.Shutdown()
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.Shutdown()
 }
 
 func (a *Authority) Authorize(ctx context.Context, ott string) ([]stepProvisioner.SignOption, error) {
-	return a.stepAuth.Authorize(ctx, ott)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.Authorize(ctx, ott)
 }
 
 func (a *Authority) AuthorizeSign(ott string) ([]stepProvisioner.SignOption, error) {
-	return a.stepAuth.AuthorizeSign(ott)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.AuthorizeSign(ott)
 }
 
 func (a *Authority) GetTLSOptions() *tlsutil.TLSOptions {
-	return a.stepAuth.GetTLSOptions()
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.GetTLSOptions()
 }
 
 func (a *Authority) Root(shasum string) (*x509.Certificate, error) {
-	return a.stepAuth.Root(shasum)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.Root(shasum)
 }
 
 func (a *Authority) Sign(cr *x509.CertificateRequest, opts stepProvisioner.Options, signOpts ...stepProvisioner.SignOption) (*x509.Certificate, *x509.Certificate, error) {
 	if a.isOCF(signOpts) {
 		return a.OCFSign(cr, opts, signOpts...)
 	}
-	return a.stepAuth.Sign(cr, opts, signOpts...)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.Sign(cr, opts, signOpts...)
 }
 
 func (a *Authority) Renew(peer *x509.Certificate) (*x509.Certificate, *x509.Certificate, error) {
-	return a.stepAuth.Renew(peer)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.Renew(peer)
 }
 
 func (a *Authority) LoadProvisionerByCertificate(c *x509.Certificate) (stepProvisioner.Interface, error) {
-	return a.stepAuth.LoadProvisionerByCertificate(c)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.LoadProvisionerByCertificate(c)
 }
 
 func (a *Authority) LoadProvisionerByID(ID string) (stepProvisioner.Interface, error) {
-	return a.stepAuth.LoadProvisionerByID(ID)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.LoadProvisionerByID(ID)
 }
 
 func (a *Authority) GetProvisioners(cursor string, limit int) (stepProvisioner.List, string, error) {
-	return a.stepAuth.GetProvisioners(cursor, limit)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.GetProvisioners(cursor, limit)
 }
 
 func (a *Authority) Revoke(opts *authority.RevokeOptions) error {
-	return a.stepAuth.Revoke(opts)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.Revoke(opts)
 }
 
 func (a *Authority) GetEncryptedKey(kid string) (string, error) {
-	return a.stepAuth.GetEncryptedKey(kid)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.GetEncryptedKey(kid)
 }
 
 func (a *Authority) GetRoots() (federation []*x509.Certificate, err error) {
-	return a.stepAuth.GetRoots()
+	ref := a.acquireStepAuth()
+	roots, err := ref.auth.GetRoots()
+	ref.release()
+	if err != nil {
+		return nil, err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := append([]*x509.Certificate{}, roots...)
+	return append(out, a.bundledRoots...), nil
 }
 
 func (a *Authority) GetFederation() ([]*x509.Certificate, error) {
-	return a.stepAuth.GetFederation()
+	ref := a.acquireStepAuth()
+	federation, err := ref.auth.GetFederation()
+	ref.release()
+	if err != nil {
+		return nil, err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := append([]*x509.Certificate{}, federation...)
+	return append(out, a.bundledFederatedRoots...), nil
 }
 
 func (a *Authority) GetTLSCertificate() (*tls.Certificate, error) {
-	return a.stepAuth.GetTLSCertificate()
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.GetTLSCertificate()
 }
 
 func (a *Authority) SignSSH(key ssh.PublicKey, opts stepProvisioner.SSHOptions, signOpts ...stepProvisioner.SignOption) (*ssh.Certificate, error) {
-	return a.stepAuth.SignSSH(key, opts, signOpts)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.SignSSH(key, opts, signOpts)
 }
 
 func (a *Authority) GetRootCertificates() []*x509.Certificate {
-	return a.stepAuth.GetRootCertificates()
+	a.mu.RLock()
+	bundledRoots := a.bundledRoots
+	a.mu.RUnlock()
+	ref := a.acquireStepAuth()
+	roots := ref.auth.GetRootCertificates()
+	ref.release()
+	out := append([]*x509.Certificate{}, roots...)
+	return append(out, bundledRoots...)
 }
 
 func (a *Authority) SignSSHAddUser(key ssh.PublicKey, subject *ssh.Certificate) (*ssh.Certificate, error) {
-	return a.stepAuth.SignSSHAddUser(key, subject)
+	ref := a.acquireStepAuth()
+	defer ref.release()
+	return ref.auth.SignSSHAddUser(key, subject)
 }
 
 func LoadConfiguration(filename string) (*Config, error) {
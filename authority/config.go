@@ -0,0 +1,52 @@
+package authority
+
+import (
+	stepAuthority "github.com/smallstep/certificates/authority"
+)
+
+// Config extends the step-ca authority configuration with the settings
+// needed to load and operate the OCF intermediate identity.
+type Config struct {
+	*stepAuthority.Config
+
+	// Password, if set, is used to decrypt IntermediateKey.
+	Password string `json:"password,omitempty"`
+
+	// IntermediateCert and IntermediateKey are the paths to the PEM encoded
+	// OCF intermediate certificate and private key used by OCFSign.
+	IntermediateCert string `json:"intermediateCert"`
+	IntermediateKey  string `json:"intermediateKey"`
+
+	// RootsBundle and FederatedRootsBundle are paths to PEM bundles of extra
+	// roots and federated roots to trust alongside step-ca's own, e.g.
+	// manufacturer-issued roots for an OCF cloud deployment. See
+	// WithRootsBundle and WithFederatedRootsBundle.
+	RootsBundle          string `json:"rootsBundle,omitempty"`
+	FederatedRootsBundle string `json:"federatedRootsBundle,omitempty"`
+
+	// KMS configures a KMS-backed signer for the OCF intermediate identity,
+	// so the intermediate private key never needs to touch disk. If set,
+	// it replaces IntermediateKey (and Password) as the source of the
+	// intermediate signer. See WithKeyManager.
+	KMS *KMSConfig `json:"kms,omitempty"`
+}
+
+// KMSConfig configures the KMS backend (pkcs11, cloudkms, awskms, yubikey,
+// sshagentkms, ...) used to sign OCF certificates with the intermediate key.
+type KMSConfig struct {
+	// Type is the KMS backend, e.g. "pkcs11", "cloudkms", "awskms",
+	// "yubikey", or "sshagentkms".
+	Type string `json:"type"`
+
+	// Key is the URI identifying the intermediate signing key within the KMS.
+	Key string `json:"key"`
+
+	// CertificateURI is the location of the intermediate certificate. If
+	// empty, IntermediateCert is used instead.
+	CertificateURI string `json:"certificateURI,omitempty"`
+
+	// CredentialsFile and Pin are backend specific KMS options, e.g. the
+	// cloudkms/awskms credentials file or the pkcs11/yubikey PIN.
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+	Pin             string `json:"pin,omitempty"`
+}
@@ -0,0 +1,80 @@
+package authority
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/certificates/kms"
+	kmsapi "github.com/smallstep/certificates/kms/apiv1"
+	"github.com/smallstep/cli/crypto/pemutil"
+)
+
+// WithKeyManager sets a KMS-backed key manager to use for the OCF
+// intermediate signer, instead of loading the intermediate private key from
+// disk. This is required for HSM-backed (pkcs11, yubikey) or cloud-hosted
+// (cloudkms, awskms) deployments where the intermediate key must never
+// touch disk. config.KMS must still be set, since that is where the
+// signing key URI and the intermediate certificate location come from.
+func WithKeyManager(km kms.KeyManager) WrapperOption {
+	return func(a *Authority) {
+		a.keyManager = km
+	}
+}
+
+// intermediateSignerAndCert returns the issuer certificate and signer that
+// OCFSign uses to sign OCF identity certificates: the KMS-backed signer if
+// one was configured, otherwise the on-disk intermediate identity.
+func (a *Authority) intermediateSignerAndCert() (*x509.Certificate, crypto.Signer, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.intermediateSigner != nil {
+		return a.intermediateCert, a.intermediateSigner, nil
+	}
+	return a.intermediateIdentity.Crt, a.intermediateIdentity.Key, nil
+}
+
+// initKeyManager sets up the configured KMS-backed signer, if any. It must
+// run after wrapOpts have been applied so an explicit WithKeyManager option
+// takes precedence over config.KMS.
+func (a *Authority) initKeyManager(config *Config) error {
+	if a.keyManager == nil && config.KMS != nil {
+		km, err := kms.New(context.Background(), kmsapi.Options{
+			Type:            kmsapi.Type(config.KMS.Type),
+			CredentialsFile: config.KMS.CredentialsFile,
+			URI:             config.KMS.Key,
+			Pin:             config.KMS.Pin,
+		})
+		if err != nil {
+			return errors.Wrap(err, "error initializing KMS")
+		}
+		a.keyManager = km
+	}
+	if a.keyManager == nil {
+		return nil
+	}
+	if config.KMS == nil {
+		return errors.New("authority: WithKeyManager requires config.KMS to specify the signing key URI and certificate")
+	}
+
+	signer, err := a.keyManager.CreateSigner(&kmsapi.CreateSignerRequest{
+		SigningKey: config.KMS.Key,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error creating KMS signer")
+	}
+
+	certFile := config.KMS.CertificateURI
+	if certFile == "" {
+		certFile = config.IntermediateCert
+	}
+	cert, err := pemutil.ReadCertificate(certFile)
+	if err != nil {
+		return errors.Wrapf(err, "error reading %s", certFile)
+	}
+
+	a.intermediateSigner = signer
+	a.intermediateCert = cert
+	return nil
+}
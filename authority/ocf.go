@@ -0,0 +1,227 @@
+package authority
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+	stepProvisioner "github.com/smallstep/certificates/authority/provisioner"
+)
+
+// ocfCertDuration is the validity period of OCF identity certificates.
+const ocfCertDuration = 398 * 24 * time.Hour
+
+// ocfIdentityOID is the OCF device identity OID, encoded into the certificate
+// subject as specified by the OCF Security Specification.
+var ocfIdentityOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 51414, 0, 0}
+
+// ocfRoleOID is the ID of the certificate extension that holds the OCF
+// role(s) a certificate is authorized for, as an ASN.1 SEQUENCE of strings.
+// The roles are not placed in the SubjectAltName extension.
+var ocfRoleOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 51414, 0, 1}
+
+// ocfExtKeyUsageClient and ocfExtKeyUsageServer are the OCF specific
+// extended key usages applied to OCF identity certificates.
+var (
+	ocfExtKeyUsageClient = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 51414, 0, 2, 1}
+	ocfExtKeyUsageServer = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 51414, 0, 2, 2}
+)
+
+// OCFIdentity is the OCF device identity and role set to encode into a
+// certificate signed by OCFSign. It is either derived from the CSR or
+// resolved externally, see WithOCFIdentityFunc.
+type OCFIdentity struct {
+	// DeviceUUID is the OCF device identity, encoded into ocfIdentityOID.
+	DeviceUUID string
+	// Roles are the OCF roles the certificate is authorized for, encoded
+	// into ocfRoleOID.
+	Roles []string
+}
+
+// OCFIdentityFunc resolves the OCF device identity and role set for a
+// signing request from an external identity provider (LDAP, OIDC userinfo,
+// a device registry, ...), given the provisioner and token that
+// authenticated it.
+type OCFIdentityFunc func(ctx context.Context, p stepProvisioner.Interface, token string) (*OCFIdentity, error)
+
+// WithOCFIdentityFunc sets fn as the OCF identity resolver used by OCFSign.
+// When set, OCFSign calls fn instead of deriving the OCF identity solely
+// from fields embedded in the CSR.
+func WithOCFIdentityFunc(fn OCFIdentityFunc) WrapperOption {
+	return func(a *Authority) {
+		a.ocfIdentityFunc = fn
+	}
+}
+
+// ocfSignOption is implemented by the SignOption a provisioner adds to
+// AuthorizeSign when it carries the `ocf: true` attribute. It flags the
+// sign request as OCF-aware, and carries the provisioner and token OCFSign
+// needs to resolve the OCF identity.
+type ocfSignOption interface {
+	IsOCF() bool
+	OCFToken() (stepProvisioner.Interface, string)
+}
+
+// findOCFSignOption returns the first sign option that opts this request
+// into the OCF identity/role profile, if any.
+func findOCFSignOption(signOpts []stepProvisioner.SignOption) (ocfSignOption, bool) {
+	for _, so := range signOpts {
+		if p, ok := so.(ocfSignOption); ok && p.IsOCF() {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// isOCF reports whether any of the given sign options originate from an
+// OCF provisioner, in which case the certificate must go through OCFSign
+// instead of the upstream stepAuth signing path.
+func (a *Authority) isOCF(signOpts []stepProvisioner.SignOption) bool {
+	_, ok := findOCFSignOption(signOpts)
+	return ok
+}
+
+// OCFSign verifies cr, builds a certificate template from it, applies the
+// provisioner's own sign options (request validators, profile modifiers,
+// certificate validators — the same ones stepAuth.Sign would apply) on top
+// of the OCF identity/role profile (the OCF device identity OID, the OCF
+// role extension, and OCF extended key usages), and signs it against the
+// OCF intermediate identity. The issued certificate is stored via
+// a.GetDatabase(), the same as stepAuth.Sign, so it is visible to Revoke,
+// Renew, and the CA's audit trail.
+func (a *Authority) OCFSign(cr *x509.CertificateRequest, opts stepProvisioner.Options, signOpts ...stepProvisioner.SignOption) (*x509.Certificate, *x509.Certificate, error) {
+	if err := cr.CheckSignature(); err != nil {
+		return nil, nil, errors.Wrap(err, "error validating certificate request signature")
+	}
+	for _, so := range signOpts {
+		if v, ok := so.(stepProvisioner.CertificateRequestValidator); ok {
+			if err := v.Valid(cr); err != nil {
+				return nil, nil, errors.Wrap(err, "error validating certificate request")
+			}
+		}
+	}
+
+	issuer, signer, err := a.intermediateSignerAndCert()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error generating serial number")
+	}
+
+	now := time.Now()
+	notBefore := opts.NotBefore.RelativeTime(now)
+	notAfter := opts.NotAfter.RelativeTime(now)
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(ocfCertDuration)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               cr.Subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		UnknownExtKeyUsage:    []asn1.ObjectIdentifier{ocfExtKeyUsageClient, ocfExtKeyUsageServer},
+		DNSNames:              cr.DNSNames,
+		IPAddresses:           cr.IPAddresses,
+		URIs:                  cr.URIs,
+		EmailAddresses:        cr.EmailAddresses,
+		BasicConstraintsValid: true,
+	}
+
+	signOptions := stepProvisioner.SignOptions{Backdate: opts.Backdate}
+	for _, so := range signOpts {
+		if m, ok := so.(stepProvisioner.ProfileModifier); ok {
+			if err := m.Modify(template, signOptions); err != nil {
+				return nil, nil, errors.Wrap(err, "error applying certificate modifier")
+			}
+		}
+	}
+
+	identity, err := a.resolveOCFIdentity(cr, signOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if policyDB := a.getPolicyDB(); policyDB != nil {
+		policy, err := policyDB.GetRolePolicy(identity.DeviceUUID)
+		switch {
+		case err == nil:
+			identity.Roles = policy.Roles
+		case errors.Cause(err) == ErrRolePolicyNotFound:
+			// No policy row for this device yet: keep the roles resolved
+			// by OCFIdentityFunc, if any (an identity derived from the CSR
+			// carries none), instead of blocking issuance on
+			// pre-provisioning.
+		default:
+			return nil, nil, errors.Wrapf(err, "error loading OCF role policy for %s", identity.DeviceUUID)
+		}
+	}
+	template.Subject.ExtraNames = append(template.Subject.ExtraNames, pkix.AttributeTypeAndValue{
+		Type:  ocfIdentityOID,
+		Value: identity.DeviceUUID,
+	})
+	if len(identity.Roles) > 0 {
+		roles, err := asn1.Marshal(identity.Roles)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error encoding OCF roles")
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{
+			Id:    ocfRoleOID,
+			Value: roles,
+		})
+	}
+
+	for _, so := range signOpts {
+		if v, ok := so.(stepProvisioner.CertificateValidator); ok {
+			if err := v.Valid(template, signOptions); err != nil {
+				return nil, nil, errors.Wrap(err, "error validating certificate")
+			}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, cr.PublicKey, signer)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error signing OCF certificate")
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing OCF certificate")
+	}
+
+	if err := a.GetDatabase().StoreCertificate(cert); err != nil {
+		return nil, nil, errors.Wrap(err, "error storing OCF certificate")
+	}
+
+	return cert, issuer, nil
+}
+
+// resolveOCFIdentity returns the OCF identity/role set for cr: the result
+// of the configured OCFIdentityFunc if one is set and the sign options
+// carry a provisioner and token, otherwise an identity derived solely from
+// the CSR subject.
+func (a *Authority) resolveOCFIdentity(cr *x509.CertificateRequest, signOpts []stepProvisioner.SignOption) (*OCFIdentity, error) {
+	a.mu.RLock()
+	ocfIdentityFunc := a.ocfIdentityFunc
+	a.mu.RUnlock()
+
+	if ocfIdentityFunc != nil {
+		if so, ok := findOCFSignOption(signOpts); ok {
+			p, token := so.OCFToken()
+			identity, err := ocfIdentityFunc(context.Background(), p, token)
+			if err != nil {
+				return nil, errors.Wrap(err, "error resolving OCF identity")
+			}
+			return identity, nil
+		}
+	}
+
+	return &OCFIdentity{DeviceUUID: cr.Subject.CommonName}, nil
+}
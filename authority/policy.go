@@ -0,0 +1,123 @@
+package authority
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/nosql"
+)
+
+// ErrRolePolicyNotFound is returned by OCFPolicyDB.GetRolePolicy when no
+// policy has been stored for the requested device. OCFSign treats it as "no
+// override configured" rather than a hard failure, so a device can still
+// get a certificate before it's been provisioned in the policy store.
+var ErrRolePolicyNotFound = errors.New("authority: no OCF role policy found")
+
+// RolePolicy is the set of OCF roles and claims stored for a device through
+// OCFPolicyDB. OCFSign encodes Roles into the device's certificate; Claims
+// are stored and returned but not written into the certificate.
+type RolePolicy struct {
+	DeviceID string            `json:"deviceId"`
+	Roles    []string          `json:"roles"`
+	Claims   map[string]string `json:"claims,omitempty"`
+}
+
+// OCFPolicyDB persists the OCF role policy for each device, so the roles
+// OCFSign authorizes for a device can be updated at runtime
+// through GetOCFRolePolicy/PutOCFRolePolicy instead of being hard-coded.
+//
+// GetRolePolicy must return ErrRolePolicyNotFound, not a wrapped or opaque
+// error, when no policy has been stored for deviceID yet.
+type OCFPolicyDB interface {
+	GetRolePolicy(deviceID string) (*RolePolicy, error)
+	PutRolePolicy(policy *RolePolicy) error
+}
+
+// WithOCFPolicyDB sets the OCFPolicyDB OCFSign consults to authorize the
+// roles it encodes into a certificate for a device.
+func WithOCFPolicyDB(db OCFPolicyDB) WrapperOption {
+	return func(a *Authority) {
+		a.policyDB = db
+	}
+}
+
+// ocfPolicyBucket is the nosql bucket OCF role policies are stored under
+// when reusing the authority's own database via NewOCFPolicyDB.
+var ocfPolicyBucket = []byte("ocf_role_policies")
+
+// nosqlKV is the subset of a nosql.DB's bucket operations OCFPolicyDB
+// needs. a.GetDatabase() satisfies it whenever the configured db.AuthDB is
+// backed by a nosql.DB.
+type nosqlKV interface {
+	CreateTable(bucket []byte) error
+	Get(bucket, key []byte) ([]byte, error)
+	Set(bucket, key, value []byte) error
+}
+
+// nosqlPolicyDB is an OCFPolicyDB backed by a nosqlKV, reusing the
+// authority's own database rather than standing up a separate store.
+type nosqlPolicyDB struct {
+	db nosqlKV
+}
+
+// NewOCFPolicyDB returns an OCFPolicyDB backed by db, e.g. a.GetDatabase().
+func NewOCFPolicyDB(db nosqlKV) (OCFPolicyDB, error) {
+	if err := db.CreateTable(ocfPolicyBucket); err != nil {
+		return nil, errors.Wrap(err, "error creating OCF role policy table")
+	}
+	return &nosqlPolicyDB{db: db}, nil
+}
+
+func (p *nosqlPolicyDB) GetRolePolicy(deviceID string) (*RolePolicy, error) {
+	b, err := p.db.Get(ocfPolicyBucket, []byte(deviceID))
+	if err != nil {
+		if errors.Cause(err) == nosql.ErrNotFound {
+			return nil, ErrRolePolicyNotFound
+		}
+		return nil, errors.Wrapf(err, "error getting OCF role policy for %s", deviceID)
+	}
+	policy := new(RolePolicy)
+	if err := json.Unmarshal(b, policy); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling OCF role policy for %s", deviceID)
+	}
+	return policy, nil
+}
+
+func (p *nosqlPolicyDB) PutRolePolicy(policy *RolePolicy) error {
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling OCF role policy")
+	}
+	if err := p.db.Set(ocfPolicyBucket, []byte(policy.DeviceID), b); err != nil {
+		return errors.Wrapf(err, "error storing OCF role policy for %s", policy.DeviceID)
+	}
+	return nil
+}
+
+// getPolicyDB returns the configured OCFPolicyDB, guarding against a
+// concurrent Reload swapping it out from under the caller.
+func (a *Authority) getPolicyDB() OCFPolicyDB {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.policyDB
+}
+
+// GetOCFRolePolicy returns the OCF role policy for deviceID. It is the read
+// side of the CRUD a fleet operator's admin API exposes to update the
+// allowed OCF roles/claims for a device without restarting the CA.
+func (a *Authority) GetOCFRolePolicy(deviceID string) (*RolePolicy, error) {
+	policyDB := a.getPolicyDB()
+	if policyDB == nil {
+		return nil, errors.New("authority: no OCFPolicyDB configured")
+	}
+	return policyDB.GetRolePolicy(deviceID)
+}
+
+// PutOCFRolePolicy creates or updates the OCF role policy for policy.DeviceID.
+func (a *Authority) PutOCFRolePolicy(policy *RolePolicy) error {
+	policyDB := a.getPolicyDB()
+	if policyDB == nil {
+		return errors.New("authority: no OCFPolicyDB configured")
+	}
+	return policyDB.PutRolePolicy(policy)
+}
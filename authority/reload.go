@@ -0,0 +1,69 @@
+package authority
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Reload builds a fresh Authority from newConfig, reusing the existing
+// database via WithDatabase, and atomically swaps it in for the current
+// stepAuth, intermediate identity, roots bundles, and KMS signer. This lets
+// operators rotate provisioners, roots, and OCF role mappings without
+// restarting the CA process.
+//
+// The KMS key manager, OCF identity func, and OCF policy DB configured on
+// the live Authority (via WithKeyManager, WithOCFIdentityFunc, and
+// WithOCFPolicyDB) carry over to the reloaded one automatically; there is no
+// way to change them through Reload. The roots/federated roots bundle paths
+// also carry over unless newConfig sets its own.
+//
+// Reload serializes against concurrent reloads. It waits for requests that
+// acquired the old stepAuth before the swap to finish before shutting it
+// down, so none of them have their database or connections pulled out from
+// under them mid-flight.
+func (a *Authority) Reload(newConfig *Config) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	a.mu.RLock()
+	keyManager := a.keyManager
+	ocfIdentityFunc := a.ocfIdentityFunc
+	policyDB := a.policyDB
+	if newConfig.RootsBundle == "" {
+		newConfig.RootsBundle = a.rootsBundle
+	}
+	if newConfig.FederatedRootsBundle == "" {
+		newConfig.FederatedRootsBundle = a.federatedRootsBundle
+	}
+	a.mu.RUnlock()
+
+	newAuth, err := New(newConfig,
+		WithDatabase(a.GetDatabase()),
+		WrapperOption(func(na *Authority) {
+			na.keyManager = keyManager
+			na.ocfIdentityFunc = ocfIdentityFunc
+			na.policyDB = policyDB
+		}),
+	)
+	if err != nil {
+		return errors.Wrap(err, "error reloading authority")
+	}
+
+	a.mu.Lock()
+	oldRef := a.stepAuthRef
+	a.config = newAuth.config
+	a.stepAuthRef = newAuth.stepAuthRef
+	a.intermediateIdentity = newAuth.intermediateIdentity
+	a.rootsBundle = newAuth.rootsBundle
+	a.federatedRootsBundle = newAuth.federatedRootsBundle
+	a.bundledRoots = newAuth.bundledRoots
+	a.bundledFederatedRoots = newAuth.bundledFederatedRoots
+	a.keyManager = newAuth.keyManager
+	a.intermediateSigner = newAuth.intermediateSigner
+	a.intermediateCert = newAuth.intermediateCert
+	a.ocfIdentityFunc = newAuth.ocfIdentityFunc
+	a.policyDB = newAuth.policyDB
+	a.mu.Unlock()
+
+	oldRef.wg.Wait()
+	return oldRef.auth.Shutdown()
+}
@@ -0,0 +1,41 @@
+package authority
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// loadCertBundle reads every PEM encoded CERTIFICATE block in path and
+// returns them as parsed certificates. It is used to load the roots and
+// federated roots bundles set by WithRootsBundle and WithFederatedRootsBundle.
+func loadCertBundle(path string) ([]*x509.Certificate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", path)
+	}
+
+	var certs []*x509.Certificate
+	for len(b) > 0 {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing certificate in %s", path)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.Errorf("no certificates found in %s", path)
+	}
+	return certs, nil
+}
@@ -0,0 +1,24 @@
+package authority
+
+import (
+	scep "github.com/smallstep/certificates/scep"
+)
+
+// OCFSCEP wraps an upstream SCEP authority so that CSRs enrolled through
+// SCEP for an OCF provisioner (one with `ocf: true` set) go through the
+// same OCF identity/role profile as OCFSign, instead of stepAuth's signing
+// path directly.
+type OCFSCEP struct {
+	*scep.Authority
+}
+
+// NewOCFSCEP returns a SCEP authority backed by a. Because the SCEP
+// authority signs CSRs through a.Sign, requests coming from an OCF
+// provisioner are transparently routed through OCFSign.
+func NewOCFSCEP(a *Authority, opts ...scep.AuthorityOption) (*OCFSCEP, error) {
+	scepAuth, err := scep.NewAuthority(a.GetDatabase(), a, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &OCFSCEP{Authority: scepAuth}, nil
+}